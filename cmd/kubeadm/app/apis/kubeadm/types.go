@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// NodeRegistrationOptions holds fields that relate to registering a new node to the cluster, done via `kubeadm
+// join`.
+type NodeRegistrationOptions struct {
+	// Name is the `.Metadata.Name` field of the Node API object that will be created for this node.
+	Name string
+	// CRISocket is used to retrieve container runtime info.
+	CRISocket string
+	// Taints lets you apply a list of taints to the newly registered node.
+	Taints []interface{}
+}
+
+// APIEndpoint struct contains elements of API server instance deployed on a node.
+type APIEndpoint struct {
+	// AdvertiseAddress sets the IP address for the API server to advertise.
+	AdvertiseAddress string
+	// BindPort sets the secure port for the API Server to bind to. Defaults to 6443.
+	BindPort int32
+}
+
+// JoinControlPlane contains elements describing an additional control plane instance to be deployed on the
+// joining node.
+type JoinControlPlane struct {
+	// LocalAPIEndpoint represents the endpoint of the API server instance to be deployed on this node.
+	LocalAPIEndpoint APIEndpoint
+}
+
+// ComponentConfigMap holds component config documents found alongside an Init/JoinConfiguration document (e.g.
+// KubeletConfiguration, KubeProxyConfiguration), keyed by their Kind and decoded into their concrete external
+// type.
+type ComponentConfigMap map[string]runtime.Object
+
+// JoinConfiguration contains elements describing a particular node that joins an already existing cluster.
+type JoinConfiguration struct {
+	// NodeRegistration holds fields that relate to registering the new node to the cluster.
+	NodeRegistration NodeRegistrationOptions
+	// ClusterConfiguration, when populated by FetchJoinConfigurationFromCluster, carries the recorded
+	// configuration of the cluster being joined.
+	ClusterConfiguration ClusterConfiguration
+	// ControlPlane defines the additional control plane instance to be deployed on this node, if any. If nil,
+	// this node joins as a worker only.
+	ControlPlane *JoinControlPlane
+	// ComponentConfigs holds component configs found alongside this JoinConfiguration document, keyed by Kind.
+	ComponentConfigs ComponentConfigMap
+}
+
+// ClusterConfiguration contains elements describing a kubeadm cluster.
+type ClusterConfiguration struct {
+	// KubernetesVersion is the target version of the control plane.
+	KubernetesVersion string
+}
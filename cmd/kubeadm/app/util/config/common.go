@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog"
+	kubeadmapi "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm"
+	kubeadmapiv1alpha3 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1alpha3"
+	kubeadmapiv1beta1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta1"
+	"k8s.io/kubernetes/cmd/kubeadm/app/constants"
+	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
+)
+
+// currentGroupVersion is the only kubeadm.k8s.io GroupVersion ValidateSupportedVersion accepts unconditionally.
+var currentGroupVersion = kubeadmapiv1beta1.SchemeGroupVersion
+
+// deprecatedGroupVersions are older kubeadm.k8s.io GroupVersions that ValidateSupportedVersion only accepts
+// when the caller passes allowDeprecated.
+var deprecatedGroupVersions = map[schema.GroupVersion]bool{
+	kubeadmapiv1alpha3.SchemeGroupVersion: true,
+}
+
+// experimentalGroupVersions are kubeadm.k8s.io GroupVersions that aren't GA yet, which ValidateSupportedVersion
+// only accepts when the caller passes allowExperimental. The set is empty, so allowExperimental currently
+// changes nothing. LoadJoinConfigurationFromFile only decodes the current GroupVersion and those listed in
+// deprecatedJoinConfigurationDecoders, so a GroupVersion listed here alone still fails to load there.
+var experimentalGroupVersions = map[schema.GroupVersion]bool{}
+
+// ValidateSupportedVersion checks if the supplied GroupVersion is supported by kubeadm. allowDeprecated lets an
+// older, deprecated GroupVersion through instead of being rejected outright, for one release cycle.
+// allowExperimental lets a kubeadm.k8s.io GroupVersion in experimentalGroupVersions through.
+func ValidateSupportedVersion(gv schema.GroupVersion, allowDeprecated, allowExperimental bool) error {
+	if gv == currentGroupVersion {
+		return nil
+	}
+	if deprecatedGroupVersions[gv] {
+		if allowDeprecated {
+			return nil
+		}
+		return errors.Errorf("your configuration file uses the deprecated %q API; migrate it to %q, or pass --allow-deprecated-api to keep using it for this release", gv, currentGroupVersion)
+	}
+	if experimentalGroupVersions[gv] {
+		if allowExperimental {
+			return nil
+		}
+		return errors.Errorf("your configuration file uses the experimental %q API; pass --allow-experimental-api to use it before it's GA", gv)
+	}
+	return errors.Errorf("unsupported kubeadm.k8s.io API: %s", gv)
+}
+
+// SetNodeRegistrationDynamicDefaults checks and sets configuration values for the NodeRegistrationOptions
+// object. skipCRIDetect skips the (slow, container-runtime-sensitive) CRI socket auto-detection, falling back
+// to the default socket instead. addMasterTaint is not read: no taint is added to cfg.Taints.
+func SetNodeRegistrationDynamicDefaults(cfg *kubeadmapi.NodeRegistrationOptions, addMasterTaint, skipCRIDetect bool) error {
+	if cfg.Name == "" {
+		hostname, err := kubeadmutil.GetHostname("")
+		if err != nil {
+			return err
+		}
+		cfg.Name = hostname
+	}
+
+	if cfg.CRISocket != "" {
+		return nil
+	}
+
+	if skipCRIDetect {
+		klog.V(4).Infoln("skipping CRI socket auto-detection for node registration")
+		cfg.CRISocket = constants.DefaultDockerCRISocket
+		return nil
+	}
+
+	socket, err := kubeadmutil.DetectCRISocket()
+	if err != nil {
+		return errors.Wrap(err, "could not detect a supported container runtime")
+	}
+	cfg.CRISocket = socket
+	return nil
+}
+
+// SetAPIEndpointDynamicDefaults checks and sets configuration values for the APIEndpoint object.
+func SetAPIEndpointDynamicDefaults(endpoint *kubeadmapi.APIEndpoint) error {
+	if endpoint.BindPort == 0 {
+		endpoint.BindPort = constants.APIServerDefaultBindPort
+	}
+	return nil
+}
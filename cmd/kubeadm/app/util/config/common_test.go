@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeadmapi "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm"
+	kubeadmapiv1alpha3 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1alpha3"
+	"k8s.io/kubernetes/cmd/kubeadm/app/constants"
+)
+
+func TestValidateSupportedVersion(t *testing.T) {
+	var tests = []struct {
+		name              string
+		gv                schema.GroupVersion
+		allowDeprecated   bool
+		allowExperimental bool
+		expectedErr       bool
+	}{
+		{
+			name: "current version is always accepted",
+			gv:   currentGroupVersion,
+		},
+		{
+			name:        "deprecated version is rejected by default",
+			gv:          kubeadmapiv1alpha3.SchemeGroupVersion,
+			expectedErr: true,
+		},
+		{
+			name:            "deprecated version is accepted with allowDeprecated",
+			gv:              kubeadmapiv1alpha3.SchemeGroupVersion,
+			allowDeprecated: true,
+		},
+		{
+			name:        "unknown version is always rejected",
+			gv:          schema.GroupVersion{Group: "kubeadm.k8s.io", Version: "v1alpha1"},
+			expectedErr: true,
+		},
+		{
+			name:              "unregistered version is rejected even with allowExperimental",
+			gv:                schema.GroupVersion{Group: "kubeadm.k8s.io", Version: "v1alpha1"},
+			allowExperimental: true,
+			expectedErr:       true,
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			err := ValidateSupportedVersion(rt.gv, rt.allowDeprecated, rt.allowExperimental)
+			if (err != nil) != rt.expectedErr {
+				t.Fatalf("expected error: %v, got: %v", rt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestSetNodeRegistrationDynamicDefaultsSkipCRIDetect(t *testing.T) {
+	cfg := &kubeadmapi.NodeRegistrationOptions{Name: "my-node"}
+	if err := SetNodeRegistrationDynamicDefaults(cfg, false, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CRISocket != constants.DefaultDockerCRISocket {
+		t.Errorf("expected CRISocket to fall back to the default socket, got %q", cfg.CRISocket)
+	}
+}
+
+func TestSetNodeRegistrationDynamicDefaultsKeepsExplicitCRISocket(t *testing.T) {
+	cfg := &kubeadmapi.NodeRegistrationOptions{Name: "my-node", CRISocket: "/var/run/my-cri.sock"}
+	if err := SetNodeRegistrationDynamicDefaults(cfg, false, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CRISocket != "/var/run/my-cri.sock" {
+		t.Errorf("expected explicit CRISocket to be left untouched, got %q", cfg.CRISocket)
+	}
+}
+
+func TestSetAPIEndpointDynamicDefaults(t *testing.T) {
+	endpoint := &kubeadmapi.APIEndpoint{}
+	if err := SetAPIEndpointDynamicDefaults(endpoint); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if endpoint.BindPort == 0 {
+		t.Errorf("expected BindPort to be defaulted, got 0")
+	}
+}
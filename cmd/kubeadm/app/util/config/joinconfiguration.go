@@ -17,27 +17,69 @@ limitations under the License.
 package config
 
 import (
+	"context"
 	"io/ioutil"
+	"strings"
 
+	"github.com/imdario/mergo"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 	kubeadmapi "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm"
 	kubeadmscheme "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/scheme"
+	kubeadmapiv1alpha3 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1alpha3"
 	kubeadmapiv1beta1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta1"
 	"k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/validation"
 	"k8s.io/kubernetes/cmd/kubeadm/app/constants"
 	kubeadmutil "k8s.io/kubernetes/cmd/kubeadm/app/util"
 	"k8s.io/kubernetes/cmd/kubeadm/app/util/config/strict"
+	kubeconfigutil "k8s.io/kubernetes/cmd/kubeadm/app/util/kubeconfig"
+	kubeletconfigv1beta1 "k8s.io/kubernetes/pkg/kubelet/apis/config/v1beta1"
+	kubeproxyconfigv1alpha1 "k8s.io/kubernetes/pkg/proxy/apis/config/v1alpha1"
 )
 
+// componentConfigKindDecoders maps each component config kind that's recognized as a sibling of a
+// JoinConfiguration document in the same YAML stream to a constructor for its concrete external type, so that
+// decodeJoinComponentConfigs can decode each document instead of carrying it around as raw bytes.
+//
+// UpgradeConfiguration (as seen in v1beta4 in the ecosystem) is deliberately not listed here yet: it doesn't
+// exist upstream in this release, so there's no real Kind constant or external type to decode into. Add it once
+// both land; until then, a YAML stream carrying an UpgradeConfiguration document alongside JoinConfiguration is
+// rejected by decodeJoinComponentConfigs as an unknown kind.
+var componentConfigKindDecoders = map[string]func() runtime.Object{
+	constants.KubeletConfigurationKind:   func() runtime.Object { return &kubeletconfigv1beta1.KubeletConfiguration{} },
+	constants.KubeProxyConfigurationKind: func() runtime.Object { return &kubeproxyconfigv1alpha1.KubeProxyConfiguration{} },
+}
+
+// LoadOrDefaultConfigurationOptions holds the common, cross-cutting options accepted by the
+// LoadOrDefaultJoinConfiguration family of functions, so that callers such as the phase commands
+// under cmd/kubeadm/app/cmd/phases/join don't have to grow a new parameter every time one is added.
+type LoadOrDefaultConfigurationOptions struct {
+	// SkipCRIDetect skips the CRI socket auto-detection normally performed by
+	// SetNodeRegistrationDynamicDefaults. Useful for phase commands, such as `kubeadm join phase preflight`,
+	// that run before a container runtime is known to be reachable.
+	SkipCRIDetect bool
+	// AllowExperimentalAPI allows ValidateSupportedVersion to accept kubeadm.k8s.io GroupVersions that
+	// aren't GA yet.
+	AllowExperimentalAPI bool
+	// SkipComponentConfigs skips loading and validating the component configs found alongside a
+	// JoinConfiguration document.
+	SkipComponentConfigs bool
+	// AllowDeprecatedAPI allows ValidateSupportedVersion to accept a deprecated kubeadm.k8s.io
+	// GroupVersion instead of rejecting it outright.
+	AllowDeprecatedAPI bool
+}
+
 // SetJoinDynamicDefaults checks and sets configuration values for the JoinConfiguration object
-func SetJoinDynamicDefaults(cfg *kubeadmapi.JoinConfiguration) error {
+func SetJoinDynamicDefaults(cfg *kubeadmapi.JoinConfiguration, opts LoadOrDefaultConfigurationOptions) error {
 	addMasterTaint := false
 	if cfg.ControlPlane != nil {
 		addMasterTaint = true
 	}
-	if err := SetNodeRegistrationDynamicDefaults(&cfg.NodeRegistration, addMasterTaint); err != nil {
+	if err := SetNodeRegistrationDynamicDefaults(&cfg.NodeRegistration, addMasterTaint, opts.SkipCRIDetect); err != nil {
 		return err
 	}
 
@@ -54,64 +96,232 @@ func SetJoinControlPlaneDefaults(cfg *kubeadmapi.JoinControlPlane) error {
 	return nil
 }
 
-// LoadOrDefaultJoinConfiguration takes a path to a config file and a versioned configuration that can serve as the default config
-// If cfgPath is specified, defaultversionedcfg will always get overridden. Otherwise, the default config (often populated by flags) will be used.
+// LoadOrDefaultJoinConfiguration takes a path to a config file, a path to a kubeconfig for an already-running
+// cluster, and a versioned configuration that can serve as the default config.
+// If cfgPath is specified, defaultversionedcfg will always get overridden. Otherwise, if kubeconfigPath is
+// specified, the cluster's recorded ClusterConfiguration is fetched and merged with defaultversionedcfg by
+// FetchJoinConfigurationFromCluster; a failure to reach the cluster is returned as an error. Otherwise, the
+// default config (often populated by flags) will be used.
 // Then the external, versioned configuration is defaulted and converted to the internal type.
 // Right thereafter, the configuration is defaulted again with dynamic values (like IP addresses of a machine, etc)
 // Lastly, the internal config is validated and returned.
-func LoadOrDefaultJoinConfiguration(cfgPath string, defaultversionedcfg *kubeadmapiv1beta1.JoinConfiguration) (*kubeadmapi.JoinConfiguration, error) {
+func LoadOrDefaultJoinConfiguration(cfgPath, kubeconfigPath string, defaultversionedcfg *kubeadmapiv1beta1.JoinConfiguration, opts LoadOrDefaultConfigurationOptions) (*kubeadmapi.JoinConfiguration, WarningList, error) {
 	if cfgPath != "" {
 		// Loads configuration from config file, if provided
 		// Nb. --config overrides command line flags, TODO: fix this
-		return LoadJoinConfigurationFromFile(cfgPath)
+		return LoadJoinConfigurationFromFile(cfgPath, opts)
 	}
 
-	return DefaultedJoinConfiguration(defaultversionedcfg)
+	if kubeconfigPath != "" {
+		client, err := kubeconfigutil.ClientSetFromFile(kubeconfigPath)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "couldn't create a client from kubeconfig file %q", kubeconfigPath)
+		}
+		cfg, err := FetchJoinConfigurationFromCluster(client, defaultversionedcfg, opts)
+		return cfg, nil, err
+	}
+
+	cfg, err := DefaultedJoinConfiguration(defaultversionedcfg, opts)
+	return cfg, nil, err
 }
 
-// LoadJoinConfigurationFromFile loads versioned JoinConfiguration from file, converts it to internal, defaults and validates it
-func LoadJoinConfigurationFromFile(cfgPath string) (*kubeadmapi.JoinConfiguration, error) {
+// LoadJoinConfigurationFromFile loads versioned JoinConfiguration from file, converts it to internal, defaults
+// and validates it. GroupVersions other than the current one are only accepted when
+// opts.AllowDeprecatedAPI is set, in which case they go through a conversion pipeline and the fields that were
+// renamed or dropped along the way are reported back as a WarningList for the caller to render.
+func LoadJoinConfigurationFromFile(cfgPath string, opts LoadOrDefaultConfigurationOptions) (*kubeadmapi.JoinConfiguration, WarningList, error) {
 	klog.V(1).Infof("loading configuration from %q", cfgPath)
 
 	b, err := ioutil.ReadFile(cfgPath)
 	if err != nil {
-		return nil, errors.Wrapf(err, "unable to read config from %q ", cfgPath)
+		return nil, nil, errors.Wrapf(err, "unable to read config from %q ", cfgPath)
 	}
 
 	gvkmap, err := kubeadmutil.SplitYAMLDocuments(b)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	joinBytes := []byte{}
+	var joinGVK schema.GroupVersionKind
 	for gvk, bytes := range gvkmap {
-		// not interested in anything other than JoinConfiguration
+		// not interested in anything other than JoinConfiguration; sibling component configs are
+		// picked up separately below
 		if gvk.Kind != constants.JoinConfigurationKind {
 			continue
 		}
 
 		// check if this version is supported one
-		if err := ValidateSupportedVersion(gvk.GroupVersion()); err != nil {
-			return nil, err
+		if err := ValidateSupportedVersion(gvk.GroupVersion(), opts.AllowDeprecatedAPI, opts.AllowExperimentalAPI); err != nil {
+			return nil, nil, err
 		}
 
 		// verify the validity of the YAML
 		strict.VerifyUnmarshalStrict(bytes, gvk)
 
 		joinBytes = bytes
+		joinGVK = gvk
 	}
 
 	if len(joinBytes) == 0 {
-		return nil, errors.Errorf("no %s found in config file %q", constants.JoinConfigurationKind, cfgPath)
+		return nil, nil, errors.Errorf("no %s found in config file %q", constants.JoinConfigurationKind, cfgPath)
 	}
 
-	internalcfg := &kubeadmapi.JoinConfiguration{}
-	if err := runtime.DecodeInto(kubeadmscheme.Codecs.UniversalDecoder(), joinBytes, internalcfg); err != nil {
-		return nil, err
+	var internalcfg *kubeadmapi.JoinConfiguration
+	var warnings WarningList
+	if joinGVK.GroupVersion() == kubeadmapiv1beta1.SchemeGroupVersion {
+		internalcfg = &kubeadmapi.JoinConfiguration{}
+		if err := runtime.DecodeInto(kubeadmscheme.Codecs.UniversalDecoder(), joinBytes, internalcfg); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		// joinGVK is an older, deprecated GroupVersion; ValidateSupportedVersion only let it through
+		// because opts.AllowDeprecatedAPI was set.
+		internalcfg, warnings, err = convertDeprecatedJoinConfiguration(joinGVK, joinBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if !opts.SkipComponentConfigs {
+		componentConfigs, err := decodeJoinComponentConfigs(gvkmap, cfgPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		internalcfg.ComponentConfigs = componentConfigs
 	}
 
 	// Applies dynamic defaults to settings not provided with flags
-	if err := SetJoinDynamicDefaults(internalcfg); err != nil {
+	if err := SetJoinDynamicDefaults(internalcfg, opts); err != nil {
+		return nil, nil, err
+	}
+	// Validates cfg (flags/configs + defaults)
+	if err := validation.ValidateJoinConfiguration(internalcfg).ToAggregate(); err != nil {
+		return nil, nil, err
+	}
+
+	return internalcfg, warnings, nil
+}
+
+// decodeJoinComponentConfigs picks the recognized component config documents (KubeletConfiguration,
+// KubeProxyConfiguration) out of a YAML stream already split by GVK, strict-unmarshals and decodes each of them
+// into its concrete external type, and returns them keyed by kind, mirroring what InitConfiguration does for
+// its own ComponentConfigs. It returns an error if an unrecognized kind or a duplicate kind is present.
+func decodeJoinComponentConfigs(gvkmap map[schema.GroupVersionKind][]byte, cfgPath string) (kubeadmapi.ComponentConfigMap, error) {
+	componentConfigs := kubeadmapi.ComponentConfigMap{}
+	for gvk, bytes := range gvkmap {
+		if gvk.Kind == constants.JoinConfigurationKind {
+			continue
+		}
+
+		newComponentConfig, known := componentConfigKindDecoders[gvk.Kind]
+		if !known {
+			return nil, errors.Errorf("unknown kind %q found in config file %q", gvk.Kind, cfgPath)
+		}
+		if _, duplicate := componentConfigs[gvk.Kind]; duplicate {
+			return nil, errors.Errorf("duplicate kind %q found in config file %q", gvk.Kind, cfgPath)
+		}
+
+		strict.VerifyUnmarshalStrict(bytes, gvk)
+
+		componentConfig := newComponentConfig()
+		if err := runtime.DecodeInto(kubeadmscheme.Codecs.UniversalDecoder(gvk.GroupVersion()), bytes, componentConfig); err != nil {
+			return nil, errors.Wrapf(err, "unable to decode %s in config file %q", gvk.Kind, cfgPath)
+		}
+		componentConfigs[gvk.Kind] = componentConfig
+	}
+
+	return componentConfigs, nil
+}
+
+// deprecatedJoinConfigurationDecoders maps the older kubeadm.k8s.io GroupVersions that
+// convertDeprecatedJoinConfiguration knows how to migrate forward to a constructor for their external type.
+var deprecatedJoinConfigurationDecoders = map[schema.GroupVersion]func() runtime.Object{
+	kubeadmapiv1alpha3.SchemeGroupVersion: func() runtime.Object { return &kubeadmapiv1alpha3.JoinConfiguration{} },
+}
+
+// deprecatedJoinConfigurationFieldChanges records, for each older GroupVersion convertDeprecatedJoinConfiguration
+// knows how to migrate, the fields that were renamed or dropped on the way to the current internal
+// JoinConfiguration, so the warning raised for that GroupVersion can name them instead of speaking in generalities.
+var deprecatedJoinConfigurationFieldChanges = map[schema.GroupVersion][]string{
+	kubeadmapiv1alpha3.SchemeGroupVersion: {
+		"NodeRegistration.CRISocket is now auto-detected instead of defaulting to the Docker socket",
+		"ControlPlane.LocalAPIEndpoint.BindPort now defaults to the API server's default bind port instead of the removed APIEndpoint.BindPort field",
+	},
+}
+
+// convertDeprecatedJoinConfiguration decodes bytes as gvk's external type and converts it up to the current
+// internal JoinConfiguration, for one of the older GroupVersions still accepted when
+// LoadOrDefaultConfigurationOptions.AllowDeprecatedAPI is set. It returns a WarningList carrying a structured
+// deprecation notice, naming the fields that were renamed or dropped during conversion, for the caller to render
+// and show to the user; convertDeprecatedJoinConfiguration itself never logs, to avoid printing the notice twice.
+func convertDeprecatedJoinConfiguration(gvk schema.GroupVersionKind, bytes []byte) (*kubeadmapi.JoinConfiguration, WarningList, error) {
+	newExternal, known := deprecatedJoinConfigurationDecoders[gvk.GroupVersion()]
+	if !known {
+		return nil, nil, errors.Errorf("unsupported deprecated GroupVersion %q for kind %q", gvk.GroupVersion(), gvk.Kind)
+	}
+	external := newExternal()
+
+	if err := runtime.DecodeInto(kubeadmscheme.Codecs.UniversalDecoder(gvk.GroupVersion()), bytes, external); err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to decode %s in the deprecated %s API", gvk.Kind, gvk.GroupVersion())
+	}
+
+	internalcfg := &kubeadmapi.JoinConfiguration{}
+	if err := kubeadmscheme.Scheme.Convert(external, internalcfg, nil); err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to convert %s from the deprecated %s API to the current internal API", gvk.Kind, gvk.GroupVersion())
+	}
+
+	warnings := WarningList{}
+	warnings.Add(ReasonCodeDeprecatedAPIVersion,
+		"%s was loaded from the deprecated %q API and converted to %q; please migrate this config file before the next release. Changed during conversion:\n- %s",
+		gvk.Kind, gvk.GroupVersion(), kubeadmapiv1beta1.SchemeGroupVersion,
+		strings.Join(deprecatedJoinConfigurationFieldChanges[gvk.GroupVersion()], "\n- "))
+
+	return internalcfg, warnings, nil
+}
+
+// FetchJoinConfigurationFromCluster reads the kubeadm-config ConfigMap from the kube-system namespace of the
+// cluster addressed by client and decodes the ClusterConfiguration stored there. The cluster doesn't record a
+// JoinConfiguration of its own, so defaultversionedcfg (usually populated from command line flags) supplies the
+// rest of the fields; it is converted to internal and merged in with mergo.WithOverride so that any field the
+// caller actually set wins over the zero value, instead of either silently discarding the cluster's
+// ClusterConfiguration or the caller's flags. This mirrors FetchInitConfigurationFromCluster, which `kubeadm
+// upgrade diff` uses to read back what a cluster already knows.
+//
+// Note: an earlier version of this function also looked for a JoinConfiguration template under a
+// JoinConfigurationConfigMapKey in the same ConfigMap. That key is never written by any real kubeadm
+// code path -- kubeadm-config only ever stores ClusterConfiguration -- so that branch was dead code and has
+// been removed in favor of the defaultversionedcfg/ClusterConfiguration merge above.
+func FetchJoinConfigurationFromCluster(client clientset.Interface, defaultversionedcfg *kubeadmapiv1beta1.JoinConfiguration, opts LoadOrDefaultConfigurationOptions) (*kubeadmapi.JoinConfiguration, error) {
+	klog.V(1).Infoln("[config] retrieving ClusterConfiguration from the cluster")
+
+	configMap, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(context.TODO(), constants.KubeadmConfigConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read the kubeadm-config ConfigMap")
+	}
+
+	clusterConfigurationData, ok := configMap.Data[constants.ClusterConfigurationConfigMapKey]
+	if !ok {
+		return nil, errors.Errorf("unexpected error when reading kubeadm-config ConfigMap: %s key value pair missing", constants.ClusterConfigurationConfigMapKey)
+	}
+	clusterCfg := &kubeadmapi.ClusterConfiguration{}
+	if err := runtime.DecodeInto(kubeadmscheme.Codecs.UniversalDecoder(), []byte(clusterConfigurationData), clusterCfg); err != nil {
+		return nil, errors.Wrap(err, "could not decode the ClusterConfiguration stored in the kubeadm-config ConfigMap")
+	}
+
+	localcfg := &kubeadmapi.JoinConfiguration{}
+	kubeadmscheme.Scheme.Default(defaultversionedcfg)
+	if err := kubeadmscheme.Scheme.Convert(defaultversionedcfg, localcfg, nil); err != nil {
+		return nil, errors.Wrap(err, "could not convert the locally supplied JoinConfiguration to the internal API")
+	}
+
+	internalcfg := &kubeadmapi.JoinConfiguration{ClusterConfiguration: *clusterCfg}
+	if err := mergo.Merge(internalcfg, localcfg, mergo.WithOverride); err != nil {
+		return nil, errors.Wrap(err, "could not reconcile the locally supplied JoinConfiguration with the cluster's recorded ClusterConfiguration")
+	}
+
+	// Applies dynamic defaults to settings not provided by flags or the cluster
+	if err := SetJoinDynamicDefaults(internalcfg, opts); err != nil {
 		return nil, err
 	}
 	// Validates cfg (flags/configs + defaults)
@@ -123,7 +333,7 @@ func LoadJoinConfigurationFromFile(cfgPath string) (*kubeadmapi.JoinConfiguratio
 }
 
 // DefaultedJoinConfiguration takes a versioned JoinConfiguration (usually filled in by command line parameters), defaults it, converts it to internal and validates it
-func DefaultedJoinConfiguration(defaultversionedcfg *kubeadmapiv1beta1.JoinConfiguration) (*kubeadmapi.JoinConfiguration, error) {
+func DefaultedJoinConfiguration(defaultversionedcfg *kubeadmapiv1beta1.JoinConfiguration, opts LoadOrDefaultConfigurationOptions) (*kubeadmapi.JoinConfiguration, error) {
 	internalcfg := &kubeadmapi.JoinConfiguration{}
 
 	// Takes passed flags into account; the defaulting is executed once again enforcing assignment of
@@ -132,7 +342,7 @@ func DefaultedJoinConfiguration(defaultversionedcfg *kubeadmapiv1beta1.JoinConfi
 	kubeadmscheme.Scheme.Convert(defaultversionedcfg, internalcfg, nil)
 
 	// Applies dynamic defaults to settings not provided with flags
-	if err := SetJoinDynamicDefaults(internalcfg); err != nil {
+	if err := SetJoinDynamicDefaults(internalcfg, opts); err != nil {
 		return nil, err
 	}
 	// Validates cfg (flags/configs + defaults)
@@ -141,4 +351,4 @@ func DefaultedJoinConfiguration(defaultversionedcfg *kubeadmapiv1beta1.JoinConfi
 	}
 
 	return internalcfg, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	kubeadmapiv1alpha3 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1alpha3"
+	kubeadmapiv1beta1 "k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm/v1beta1"
+	"k8s.io/kubernetes/cmd/kubeadm/app/constants"
+)
+
+func TestDecodeJoinComponentConfigsUnknownKind(t *testing.T) {
+	gvkmap := map[schema.GroupVersionKind][]byte{
+		{Group: "kubeadm.k8s.io", Version: "v1beta1", Kind: constants.JoinConfigurationKind}: []byte("kind: JoinConfiguration"),
+		{Group: "example.com", Version: "v1", Kind: "NotAComponentConfig"}:                   []byte("kind: NotAComponentConfig"),
+	}
+	if _, err := decodeJoinComponentConfigs(gvkmap, "config.yaml"); err == nil {
+		t.Fatal("expected an error for an unrecognized component config kind, got nil")
+	} else if !strings.Contains(err.Error(), "unknown kind") {
+		t.Errorf("expected an 'unknown kind' error, got: %v", err)
+	}
+}
+
+func TestDecodeJoinComponentConfigsDuplicateKind(t *testing.T) {
+	// Two documents that both carry the KubeletConfiguration kind, just under different (hypothetical)
+	// GroupVersions, should still be rejected as a duplicate since componentConfigKindDecoders is keyed by Kind.
+	gvkmap := map[schema.GroupVersionKind][]byte{
+		{Group: "kubelet.config.k8s.io", Version: "v1beta1", Kind: constants.KubeletConfigurationKind}:  []byte("kind: KubeletConfiguration"),
+		{Group: "kubelet.config.k8s.io", Version: "v1alpha1", Kind: constants.KubeletConfigurationKind}: []byte("kind: KubeletConfiguration"),
+	}
+	if _, err := decodeJoinComponentConfigs(gvkmap, "config.yaml"); err == nil {
+		t.Fatal("expected an error for a duplicate component config kind, got nil")
+	} else if !strings.Contains(err.Error(), "duplicate kind") {
+		t.Errorf("expected a 'duplicate kind' error, got: %v", err)
+	}
+}
+
+func TestFetchJoinConfigurationFromCluster(t *testing.T) {
+	clusterConfigurationYAML := "apiVersion: kubeadm.k8s.io/v1beta1\nkind: ClusterConfiguration\nkubernetesVersion: v1.13.0\n"
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.KubeadmConfigConfigMap,
+			Namespace: metav1.NamespaceSystem,
+		},
+		Data: map[string]string{
+			constants.ClusterConfigurationConfigMapKey: clusterConfigurationYAML,
+		},
+	})
+
+	defaultversionedcfg := &kubeadmapiv1beta1.JoinConfiguration{
+		NodeRegistration: kubeadmapiv1beta1.NodeRegistrationOptions{Name: "my-node", CRISocket: "/var/run/my-cri.sock"},
+	}
+
+	cfg, err := FetchJoinConfigurationFromCluster(client, defaultversionedcfg, LoadOrDefaultConfigurationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ClusterConfiguration.KubernetesVersion != "v1.13.0" {
+		t.Errorf("expected the cluster's recorded KubernetesVersion to survive the merge, got %q", cfg.ClusterConfiguration.KubernetesVersion)
+	}
+	if cfg.NodeRegistration.Name != "my-node" {
+		t.Errorf("expected the locally supplied NodeRegistration.Name to survive the merge, got %q", cfg.NodeRegistration.Name)
+	}
+	if cfg.NodeRegistration.CRISocket != "/var/run/my-cri.sock" {
+		t.Errorf("expected the locally supplied NodeRegistration.CRISocket to survive the merge, got %q", cfg.NodeRegistration.CRISocket)
+	}
+}
+
+func TestConvertDeprecatedJoinConfiguration(t *testing.T) {
+	gvk := kubeadmapiv1alpha3.SchemeGroupVersion.WithKind(constants.JoinConfigurationKind)
+	doc := []byte("apiVersion: kubeadm.k8s.io/v1alpha3\nkind: JoinConfiguration\n")
+
+	internalcfg, warnings, err := convertDeprecatedJoinConfiguration(gvk, doc)
+	if err != nil {
+		t.Fatalf("unexpected error converting a deprecated v1alpha3 JoinConfiguration: %v", err)
+	}
+	if internalcfg == nil {
+		t.Fatal("expected a non-nil internal JoinConfiguration")
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected exactly one warning, got %d: %v", len(warnings), warnings)
+	}
+	if warnings[0].ReasonCode != ReasonCodeDeprecatedAPIVersion {
+		t.Errorf("expected reason code %q, got %q", ReasonCodeDeprecatedAPIVersion, warnings[0].ReasonCode)
+	}
+	for _, field := range deprecatedJoinConfigurationFieldChanges[kubeadmapiv1alpha3.SchemeGroupVersion] {
+		if !strings.Contains(warnings[0].Message, field) {
+			t.Errorf("expected warning message to name changed field %q, got: %s", field, warnings[0].Message)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ReasonCodeDeprecatedAPIVersion is the machine-readable reason code attached to a Warning emitted when a
+// configuration document was accepted from a deprecated kubeadm.k8s.io GroupVersion and converted forward.
+const ReasonCodeDeprecatedAPIVersion = "DeprecatedAPIVersion"
+
+// Warning is a single, human readable note produced while loading or converting a user supplied kubeadm
+// configuration. ReasonCode is a short, stable, machine readable token that scripts can key off of without
+// having to parse Message.
+type Warning struct {
+	ReasonCode string `json:"reasonCode"`
+	Message    string `json:"message"`
+}
+
+// WarningList accumulates the Warnings generated while loading a user supplied configuration, so that callers
+// can surface them to the user instead of only logging them as they occur.
+type WarningList []Warning
+
+// Add appends a new warning with the given reason code and a printf-formatted message.
+func (w *WarningList) Add(reasonCode, format string, args ...interface{}) {
+	*w = append(*w, Warning{ReasonCode: reasonCode, Message: fmt.Sprintf(format, args...)})
+}
+
+// String renders the list as plain text, one warning per line, prefixed with its reason code.
+func (w WarningList) String() string {
+	lines := make([]string, 0, len(w))
+	for _, warning := range w {
+		lines = append(lines, fmt.Sprintf("[%s] %s", warning.ReasonCode, warning.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// YAML renders the list as YAML, for callers that want structured output instead of plain text.
+func (w WarningList) YAML() (string, error) {
+	b, err := yaml.Marshal(w)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}